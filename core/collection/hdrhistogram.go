@@ -0,0 +1,218 @@
+package collection
+
+import "math/bits"
+
+const (
+	minSignificantFigures = 1
+	maxSignificantFigures = 5
+)
+
+// hdrHistogram 是一个简化版的高动态范围直方图（HDR Histogram）。
+// 它把取值范围拆成若干个指数增长的bucket，每个bucket内部再按
+// subBucketCount等分，从而在lowestTrackableValue~highestTrackableValue
+// 整个区间内都能保持significantFigures位有效数字的精度，
+// 同时占用的内存是O(log(max/min))而不是O(max)。
+type hdrHistogram struct {
+	lowestTrackableValue       int64
+	highestTrackableValue      int64
+	significantFigures         int
+	unitMagnitude              int
+	subBucketHalfCountMagnitude int
+	subBucketCount             int
+	subBucketHalfCount         int
+	subBucketMask              int64
+	bucketCount                int
+	countsLen                  int
+	totalCount                 int64
+	counts                     []int64
+}
+
+// newHdrHistogram 按照[lowestTrackableValue, highestTrackableValue]的取值范围
+// 和significantFigures位有效数字创建一个hdrHistogram。
+func newHdrHistogram(lowestTrackableValue, highestTrackableValue int64, significantFigures int) *hdrHistogram {
+	if lowestTrackableValue < 1 {
+		lowestTrackableValue = 1
+	}
+	if highestTrackableValue < lowestTrackableValue {
+		highestTrackableValue = lowestTrackableValue
+	}
+	if significantFigures < minSignificantFigures {
+		significantFigures = minSignificantFigures
+	} else if significantFigures > maxSignificantFigures {
+		significantFigures = maxSignificantFigures
+	}
+
+	// 单个subBucket内至少需要能区分到significantFigures位有效数字，
+	// largestValueWithSingleUnitResolution是这一约束换算出来的最小跨度。
+	largestValueWithSingleUnitResolution := 2 * pow10(significantFigures)
+	subBucketCountMagnitude := ceilLog2(largestValueWithSingleUnitResolution)
+	subBucketHalfCountMagnitude := subBucketCountMagnitude - 1
+	if subBucketHalfCountMagnitude < 0 {
+		subBucketHalfCountMagnitude = 0
+	}
+	unitMagnitude := floorLog2(lowestTrackableValue)
+	if unitMagnitude < 0 {
+		unitMagnitude = 0
+	}
+	subBucketCount := 1 << uint(subBucketHalfCountMagnitude+1)
+	subBucketHalfCount := subBucketCount / 2
+	subBucketMask := int64(subBucketCount-1) << uint(unitMagnitude)
+
+	// bucketCount是覆盖highestTrackableValue所需要的指数bucket个数。
+	bucketCount := 1
+	smallestUntrackableValue := int64(subBucketCount) << uint(unitMagnitude)
+	for smallestUntrackableValue < highestTrackableValue {
+		if smallestUntrackableValue > (1 << 62) {
+			bucketCount++
+			break
+		}
+		smallestUntrackableValue <<= 1
+		bucketCount++
+	}
+	countsLen := (bucketCount + 1) * (subBucketCount / 2)
+
+	return &hdrHistogram{
+		lowestTrackableValue:        lowestTrackableValue,
+		highestTrackableValue:       highestTrackableValue,
+		significantFigures:          significantFigures,
+		unitMagnitude:               unitMagnitude,
+		subBucketHalfCountMagnitude: subBucketHalfCountMagnitude,
+		subBucketCount:              subBucketCount,
+		subBucketHalfCount:          subBucketHalfCount,
+		subBucketMask:               subBucketMask,
+		bucketCount:                 bucketCount,
+		countsLen:                   countsLen,
+		counts:                      make([]int64, countsLen),
+	}
+}
+
+// reset 清空统计数据，桶复用时调用。
+func (h *hdrHistogram) reset() {
+	h.totalCount = 0
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+}
+
+// recordValue 记录一个采样值，超出范围的值会被截断到highestTrackableValue。
+func (h *hdrHistogram) recordValue(v int64) {
+	if v < 0 {
+		return
+	}
+	if v > h.highestTrackableValue {
+		v = h.highestTrackableValue
+	}
+
+	idx := h.countsIndexFor(v)
+	if idx < 0 {
+		idx = 0
+	} else if idx >= h.countsLen {
+		idx = h.countsLen - 1
+	}
+	h.counts[idx]++
+	h.totalCount++
+}
+
+// mergeFrom 把另一个同规格的直方图的计数累加进来。
+func (h *hdrHistogram) mergeFrom(o *hdrHistogram) {
+	if o == nil || len(o.counts) != len(h.counts) {
+		return
+	}
+
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.totalCount += o.totalCount
+}
+
+// valueAtQuantile 返回百分位q（0~100）对应的采样值。
+func (h *hdrHistogram) valueAtQuantile(q float64) int64 {
+	if h.totalCount == 0 {
+		return 0
+	}
+	if q > 100 {
+		q = 100
+	} else if q < 0 {
+		q = 0
+	}
+
+	countAtPercentile := int64(q/100*float64(h.totalCount) + 0.5)
+	if countAtPercentile < 1 {
+		countAtPercentile = 1
+	}
+
+	var total int64
+	for i := 0; i < h.countsLen; i++ {
+		total += h.counts[i]
+		if total >= countAtPercentile {
+			bucketIndex, subBucketIndex := h.indexToBucketParts(i)
+			return h.valueFromIndex(bucketIndex, subBucketIndex)
+		}
+	}
+
+	return h.highestTrackableValue
+}
+
+func (h *hdrHistogram) getBucketIndex(v int64) int {
+	// 找到能容纳v|subBucketMask的最小2的幂，再换算成bucket下标。
+	pow2Ceiling := 64 - bits.LeadingZeros64(uint64(v)|uint64(h.subBucketMask))
+	return pow2Ceiling - h.unitMagnitude - (h.subBucketHalfCountMagnitude + 1)
+}
+
+func (h *hdrHistogram) getSubBucketIndex(v int64, bucketIndex int) int {
+	return int(v >> uint(bucketIndex+h.unitMagnitude))
+}
+
+func (h *hdrHistogram) countsIndex(bucketIndex, subBucketIndex int) int {
+	bucketBaseIndex := (bucketIndex + 1) << uint(h.subBucketHalfCountMagnitude)
+	offsetInBucket := subBucketIndex - h.subBucketHalfCount
+	return bucketBaseIndex + offsetInBucket
+}
+
+func (h *hdrHistogram) countsIndexFor(v int64) int {
+	bucketIndex := h.getBucketIndex(v)
+	if bucketIndex < 0 {
+		bucketIndex = 0
+	}
+	subBucketIndex := h.getSubBucketIndex(v, bucketIndex)
+	return h.countsIndex(bucketIndex, subBucketIndex)
+}
+
+func (h *hdrHistogram) indexToBucketParts(index int) (bucketIndex, subBucketIndex int) {
+	subBucketIndex = (index & (h.subBucketHalfCount - 1)) + h.subBucketHalfCount
+	bucketIndex = (index >> uint(h.subBucketHalfCountMagnitude)) - 1
+	if bucketIndex < 0 {
+		bucketIndex = 0
+		subBucketIndex = index
+	}
+	return
+}
+
+func (h *hdrHistogram) valueFromIndex(bucketIndex, subBucketIndex int) int64 {
+	return int64(subBucketIndex) << uint(bucketIndex+h.unitMagnitude)
+}
+
+func pow10(n int) float64 {
+	r := 1.0
+	for i := 0; i < n; i++ {
+		r *= 10
+	}
+	return r
+}
+
+func ceilLog2(v float64) int {
+	n := 0
+	p := 1.0
+	for p < v {
+		p *= 2
+		n++
+	}
+	return n
+}
+
+func floorLog2(v int64) int {
+	if v <= 0 {
+		return 0
+	}
+	return 63 - bits.LeadingZeros64(uint64(v))
+}
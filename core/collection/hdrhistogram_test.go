@@ -0,0 +1,82 @@
+package collection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHdrHistogramEmpty(t *testing.T) {
+	h := newHdrHistogram(1, 1000, 2)
+	assert.Equal(t, int64(0), h.valueAtQuantile(50))
+}
+
+func TestHdrHistogramRecordAndQuantile(t *testing.T) {
+	h := newHdrHistogram(1, 10000, 3)
+	for i := int64(1); i <= 1000; i++ {
+		h.recordValue(i)
+	}
+
+	median := h.valueAtQuantile(50)
+	// 3位有效数字下，中位数的量化误差应该在1%以内
+	assert.InDelta(t, 500, median, 10)
+
+	p100 := h.valueAtQuantile(100)
+	assert.InDelta(t, 1000, p100, 10)
+}
+
+func TestHdrHistogramClampsAboveHighest(t *testing.T) {
+	h := newHdrHistogram(1, 1000, 2)
+	h.recordValue(1000000)
+
+	v := h.valueAtQuantile(100)
+	assert.True(t, v > 0)
+	assert.True(t, v <= h.highestTrackableValue)
+}
+
+func TestHdrHistogramRecordValueIgnoresNegative(t *testing.T) {
+	h := newHdrHistogram(1, 1000, 2)
+	h.recordValue(-1)
+	assert.Equal(t, int64(0), h.totalCount)
+}
+
+func TestHdrHistogramResetClearsCounts(t *testing.T) {
+	h := newHdrHistogram(1, 1000, 2)
+	h.recordValue(100)
+	assert.Equal(t, int64(1), h.totalCount)
+
+	h.reset()
+	assert.Equal(t, int64(0), h.totalCount)
+	assert.Equal(t, int64(0), h.valueAtQuantile(50))
+}
+
+func TestHdrHistogramMerge(t *testing.T) {
+	a := newHdrHistogram(1, 1000, 2)
+	b := newHdrHistogram(1, 1000, 2)
+
+	for i := int64(1); i <= 100; i++ {
+		a.recordValue(i)
+	}
+	for i := int64(901); i <= 1000; i++ {
+		b.recordValue(i)
+	}
+
+	agg := newHdrHistogram(1, 1000, 2)
+	agg.mergeFrom(a)
+	agg.mergeFrom(b)
+
+	assert.Equal(t, a.totalCount+b.totalCount, agg.totalCount)
+	// 合并后的p50应该落在a的分布里（前一半样本都来自a）
+	assert.True(t, agg.valueAtQuantile(50) <= 100)
+	// p100应该落在b的分布里
+	assert.InDelta(t, 1000, agg.valueAtQuantile(100), 20)
+}
+
+func TestHdrHistogramMergeMismatchedSpecIsNoop(t *testing.T) {
+	a := newHdrHistogram(1, 1000, 2)
+	b := newHdrHistogram(1, 2000, 2)
+	b.recordValue(100)
+
+	a.mergeFrom(b)
+	assert.Equal(t, int64(0), a.totalCount)
+}
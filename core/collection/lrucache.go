@@ -0,0 +1,231 @@
+package collection
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/lang"
+)
+
+const defaultSweepInterval = time.Second
+
+type (
+	// LRUOption LRUCache的选项函数
+	LRUOption func(c *LRUCache)
+
+	// LRUCache 是一个带TTL的LRU缓存：容量满时淘汰最久未使用的entry，
+	// 单个entry也可以单独设置过期时间。过期的entry在下一次Get时惰性淘汰，
+	// 同时有一个后台janitor按固定节奏批量清理，避免长期不访问的key一直占着内存。
+	LRUCache struct {
+		lock         sync.Mutex
+		capacity     int
+		ll           *list.List
+		items        map[string]*list.Element
+		onEvict      func(key string, v interface{})
+		sweepTicker  *time.Ticker
+		done         chan lang.PlaceholderType
+		hits         uint64
+		misses       uint64
+	}
+
+	entry struct {
+		key      string
+		value    interface{}
+		expireAt time.Time // 零值表示永不过期
+	}
+)
+
+// New 创建一个容量为capacity的LRUCache
+func New(capacity int, opts ...LRUOption) *LRUCache {
+	if capacity < 1 {
+		panic("capacity must be greater than 0")
+	}
+
+	c := &LRUCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element, capacity),
+		done:     make(chan lang.PlaceholderType),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	go c.startJanitor(defaultSweepInterval)
+
+	return c
+}
+
+// Get 按key查找value，命中的entry会被移动到链表头部
+func (c *LRUCache) Get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	ent := elem.Value.(*entry)
+	if c.expired(ent) {
+		c.removeElement(elem)
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(elem)
+	atomic.AddUint64(&c.hits, 1)
+
+	return ent.value, true
+}
+
+// Set 写入一个永不过期的key，超出容量时淘汰最久未使用的entry
+func (c *LRUCache) Set(key string, v interface{}) {
+	c.set(key, v, time.Time{})
+}
+
+// SetWithExpire 写入一个key，并在ttl之后过期
+func (c *LRUCache) SetWithExpire(key string, v interface{}, ttl time.Duration) {
+	c.set(key, v, time.Now().Add(ttl))
+}
+
+func (c *LRUCache) set(key string, v interface{}, expireAt time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		ent := elem.Value.(*entry)
+		ent.value = v
+		ent.expireAt = expireAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	elem := c.ll.PushFront(&entry{
+		key:      key,
+		value:    v,
+		expireAt: expireAt,
+	})
+	c.items[key] = elem
+
+	if c.ll.Len() > c.capacity {
+		c.removeOldest()
+	}
+}
+
+// GetOrCreate 按key查找value，命中时和Get行为一致；未命中时在同一次加锁里调用create
+// 创建并写入，避免Get+Set两次加锁之间的竞态——并发的多个请求第一次访问同一个新key时，
+// 只有一个会真正执行create，其余都会拿到同一个value。返回的bool表示是否命中了已有entry。
+func (c *LRUCache) GetOrCreate(key string, create func() interface{}) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		ent := elem.Value.(*entry)
+		if !c.expired(ent) {
+			c.ll.MoveToFront(elem)
+			atomic.AddUint64(&c.hits, 1)
+			return ent.value, true
+		}
+
+		c.removeElement(elem)
+	}
+
+	atomic.AddUint64(&c.misses, 1)
+
+	v := create()
+	elem := c.ll.PushFront(&entry{key: key, value: v})
+	c.items[key] = elem
+
+	if c.ll.Len() > c.capacity {
+		c.removeOldest()
+	}
+
+	return v, false
+}
+
+// Del 删除一个key
+func (c *LRUCache) Del(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// Len 返回当前缓存的entry数量，包括尚未被惰性清理的过期entry
+func (c *LRUCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.ll.Len()
+}
+
+// OnEvict 注册一个entry被淘汰（容量淘汰、过期淘汰、主动Del）时的回调
+func (c *LRUCache) OnEvict(fn func(key string, v interface{})) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.onEvict = fn
+}
+
+// Stats 返回累计的命中、未命中次数
+func (c *LRUCache) Stats() (hits, misses uint64) {
+	return atomic.LoadUint64(&c.hits), atomic.LoadUint64(&c.misses)
+}
+
+// Close 停止后台janitor
+func (c *LRUCache) Close() {
+	close(c.done)
+}
+
+func (c *LRUCache) startJanitor(interval time.Duration) {
+	c.sweepTicker = time.NewTicker(interval)
+	defer c.sweepTicker.Stop()
+
+	for {
+		select {
+		case <-c.sweepTicker.C:
+			c.sweepExpired()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// sweepExpired 批量清理已过期的entry，弥补惰性淘汰只在访问时触发的不足
+func (c *LRUCache) sweepExpired() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for e := c.ll.Back(); e != nil; {
+		prev := e.Prev()
+		if c.expired(e.Value.(*entry)) {
+			c.removeElement(e)
+		}
+		e = prev
+	}
+}
+
+func (c *LRUCache) expired(ent *entry) bool {
+	return !ent.expireAt.IsZero() && !ent.expireAt.After(time.Now())
+}
+
+func (c *LRUCache) removeOldest() {
+	if elem := c.ll.Back(); elem != nil {
+		c.removeElement(elem)
+	}
+}
+
+func (c *LRUCache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	ent := elem.Value.(*entry)
+	delete(c.items, ent.key)
+	if c.onEvict != nil {
+		c.onEvict(ent.key, ent.value)
+	}
+}
@@ -0,0 +1,101 @@
+package collection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCacheGetSet(t *testing.T) {
+	c := New(2)
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	_, ok = c.Get("nope")
+	assert.False(t, ok)
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	c := New(2)
+	defer c.Close()
+
+	var evicted []string
+	c.OnEvict(func(key string, v interface{}) {
+		evicted = append(evicted, key)
+	})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// 访问a，让b变成最久未使用的那个
+	c.Get("a")
+	c.Set("c", 3)
+
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.Equal(t, 2, c.Len())
+
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+}
+
+func TestLRUCacheExpire(t *testing.T) {
+	c := New(10)
+	defer c.Close()
+
+	c.SetWithExpire("a", 1, time.Millisecond)
+	time.Sleep(time.Millisecond * 10)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+}
+
+func TestLRUCacheStats(t *testing.T) {
+	c := New(10)
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Get("a")
+	c.Get("nope")
+
+	hits, misses := c.Stats()
+	assert.Equal(t, uint64(1), hits)
+	assert.Equal(t, uint64(1), misses)
+}
+
+func TestLRUCacheGetOrCreate(t *testing.T) {
+	c := New(10)
+	defer c.Close()
+
+	calls := 0
+	create := func() interface{} {
+		calls++
+		return calls
+	}
+
+	v, ok := c.GetOrCreate("a", create)
+	assert.False(t, ok)
+	assert.Equal(t, 1, v)
+
+	v, ok = c.GetOrCreate("a", create)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 1, calls)
+}
+
+func TestLRUCacheDel(t *testing.T) {
+	c := New(10)
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Del("a")
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
@@ -0,0 +1,127 @@
+package collection
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultHistogramSignificantFigures = 2
+
+type (
+	// HistogramOption 滚动直方图选项函数
+	HistogramOption func(rh *RollingHistogram)
+
+	// RollingHistogram 滚动的HDR直方图，结构和RollingWindow类似，
+	// 区别在于每个桶里保存的不是{Sum, Count}，而是一个完整的HDR直方图，
+	// 因此支持Quantile这种只能从原始分布里算出来的统计量。跨度/偏移的计算
+	// 和RollingWindow完全一样，复用同一个rollingOffset，而不是各自维护一份。
+	RollingHistogram struct {
+		lock     sync.RWMutex
+		win      *histogramWindow
+		minValue int64
+		maxValue int64
+		sigFigs  int
+		rollingOffset
+	}
+
+	histogramBucket struct {
+		hist *hdrHistogram
+	}
+
+	histogramWindow struct {
+		buckets []*histogramBucket
+		size    int
+	}
+)
+
+// NewRollingHistogram 创建滚动直方图，minValue/maxValue是采样值的合法范围，
+// 用于控制HDR直方图的内存占用和精度。
+func NewRollingHistogram(size int, interval time.Duration, minValue, maxValue int64,
+	opts ...HistogramOption) *RollingHistogram {
+	if size < 1 {
+		panic("size must be greater than 0")
+	}
+
+	rh := &RollingHistogram{
+		rollingOffset: newRollingOffset(size, interval),
+		minValue:      minValue,
+		maxValue:      maxValue,
+		sigFigs:       defaultHistogramSignificantFigures,
+	}
+	for _, opt := range opts {
+		opt(rh)
+	}
+	rh.win = newHistogramWindow(size, minValue, maxValue, rh.sigFigs)
+	return rh
+}
+
+// Record 向当前offset指向的bucket记录一个采样值v
+func (rh *RollingHistogram) Record(v int64) {
+	rh.lock.Lock()
+	defer rh.lock.Unlock()
+	rh.updateOffset(rh.win.resetBucket)
+	rh.win.record(rh.offset, v)
+}
+
+// Merge 把所有未过期的bucket合并成一个聚合的直方图，合并逻辑和
+// RollingWindow.Reduce一致：当前桶数据不完整时按ignoreCurrent决定是否跳过。
+func (rh *RollingHistogram) Merge() *hdrHistogram {
+	rh.lock.RLock()
+	defer rh.lock.RUnlock()
+
+	agg := newHdrHistogram(rh.minValue, rh.maxValue, rh.sigFigs)
+	offset, diff := rh.spanOffsetAndDiff()
+	if diff > 0 {
+		rh.win.reduce(offset, diff, func(b *histogramBucket) {
+			agg.mergeFrom(b.hist)
+		})
+	}
+
+	return agg
+}
+
+// Quantile 返回聚合直方图里分位数q（0~1）对应的值
+func (rh *RollingHistogram) Quantile(q float64) int64 {
+	return rh.Merge().valueAtQuantile(q * 100)
+}
+
+func newHistogramWindow(size int, minValue, maxValue int64, sigFigs int) *histogramWindow {
+	buckets := make([]*histogramBucket, size)
+	for i := 0; i < size; i++ {
+		buckets[i] = &histogramBucket{hist: newHdrHistogram(minValue, maxValue, sigFigs)}
+	}
+
+	return &histogramWindow{
+		buckets: buckets,
+		size:    size,
+	}
+}
+
+func (w *histogramWindow) record(offset int, v int64) {
+	w.buckets[offset%w.size].hist.recordValue(v)
+}
+
+func (w *histogramWindow) reduce(start, count int, fn func(b *histogramBucket)) {
+	for i := 0; i < count; i++ {
+		fn(w.buckets[(start+i)%w.size])
+	}
+}
+
+func (w *histogramWindow) resetBucket(offset int) {
+	w.buckets[offset%w.size].hist.reset()
+}
+
+// IgnoreCurrentHistogramBucket 和RollingWindow的IgnoreCurrentBucket含义一致，
+// 忽略当前还未采集完整的bucket。
+func IgnoreCurrentHistogramBucket() HistogramOption {
+	return func(rh *RollingHistogram) {
+		rh.ignoreCurrent = true
+	}
+}
+
+// WithHistogramSignificantFigures 设置HDR直方图的有效数字精度，默认2位。
+func WithHistogramSignificantFigures(figures int) HistogramOption {
+	return func(rh *RollingHistogram) {
+		rh.sigFigs = figures
+	}
+}
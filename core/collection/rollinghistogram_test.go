@@ -0,0 +1,25 @@
+package collection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingHistogramQuantile(t *testing.T) {
+	rh := NewRollingHistogram(10, time.Second, 1, 10000,
+		WithHistogramSignificantFigures(3))
+
+	for i := int64(1); i <= 1000; i++ {
+		rh.Record(i)
+	}
+
+	assert.InDelta(t, 500, rh.Quantile(0.5), 10)
+	assert.InDelta(t, 1000, rh.Quantile(1), 10)
+}
+
+func TestRollingHistogramEmpty(t *testing.T) {
+	rh := NewRollingHistogram(10, time.Second, 1, 10000)
+	assert.Equal(t, int64(0), rh.Quantile(0.99))
+}
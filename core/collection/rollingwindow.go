@@ -1,7 +1,6 @@
 package collection
 
 import (
-	"fmt"
 	"sync"
 	"time"
 
@@ -14,13 +13,9 @@ type (
 
 	// 滚动窗口定义
 	RollingWindow struct {
-		lock          sync.RWMutex	// 读写锁
-		size          int			// 滚动窗口大小
-		win           *window		// 滚动窗口，一个window有size个Bucket（桶）
-		interval      time.Duration	// 间隔时间
-		offset        int			// 偏移位置，用来定位当前所在的Bucket（桶）
-		ignoreCurrent bool			// 是否忽略当前的Bucket
-		lastTime      time.Duration	// 最近时间，通过从lastTime开始到现在流逝的时间和interval，就能确定现在的offset
+		lock sync.RWMutex	// 读写锁
+		win  *window		// 滚动窗口，一个window有size个Bucket（桶）
+		rollingOffset
 	}
 )
 
@@ -31,10 +26,8 @@ func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOpt
 	}
 
 	w := &RollingWindow{
-		size:     size,
-		win:      newWindow(size),
-		interval: interval,
-		lastTime: timex.Now(),
+		win:           newWindow(size),
+		rollingOffset: newRollingOffset(size, interval),
 	}
 	for _, opt := range opts {
 		opt(w)
@@ -46,7 +39,7 @@ func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOpt
 func (rw *RollingWindow) Add(v float64) {
 	rw.lock.Lock()
 	defer rw.lock.Unlock()
-	rw.updateOffset()
+	rw.updateOffset(rw.win.resetBucket)
 	rw.win.add(rw.offset, v)
 }
 
@@ -55,64 +48,12 @@ func (rw *RollingWindow) Reduce(fn func(b *Bucket)) {
 	rw.lock.RLock()
 	defer rw.lock.RUnlock()
 
-	var diff int
-	span := rw.span()
-	// ignore current bucket, because of partial data
-	if span == 0 && rw.ignoreCurrent {
-		diff = rw.size - 1
-		fmt.Println("----------------------")
-	} else {
-		diff = rw.size - span
-	}
+	offset, diff := rw.spanOffsetAndDiff()
 	if diff > 0 {
-		offset := (rw.offset + span + 1) % rw.size
 		rw.win.reduce(offset, diff, fn)
-		fmt.Printf("offset: %v, diff: %v, span: %v\n", offset, diff, span)
-	}
-}
-
-// 计算跨度
-func (rw *RollingWindow) span() int {
-	offset := int(timex.Since(rw.lastTime) / rw.interval)
-	if 0 <= offset && offset < rw.size {
-		return offset
-	} else {
-		return rw.size
 	}
 }
 
-// 更新offset值，重置过期的buckets，更新lastTime
-func (rw *RollingWindow) updateOffset() {
-	span := rw.span()
-	if span <= 0 {
-		return
-	}
-
-	offset := rw.offset
-	start := offset + 1
-	steps := start + span
-	var remainder int
-	if steps > rw.size {
-		remainder = steps - rw.size
-		steps = rw.size
-	}
-
-	// reset expired buckets
-	for i := start; i < steps; i++ {
-		rw.win.resetBucket(i)
-	}
-	for i := 0; i < remainder; i++ {
-		rw.win.resetBucket(i)
-	}
-
-	rw.offset = (offset + span) % rw.size
-	rw.lastTime = timex.Now()
-}
-
-func (rw *RollingWindow) getOffset() int{
-	return rw.offset
-}
-
 // 桶
 type Bucket struct {
 	Sum   float64	// 计算桶里数之和
@@ -165,3 +106,78 @@ func IgnoreCurrentBucket() RollingWindowOption {
 		w.ignoreCurrent = true
 	}
 }
+
+// rollingOffset是RollingWindow和RollingHistogram共用的跨度/偏移计算逻辑：
+// 两者存的桶类型不一样（{Sum,Count} vs hdrHistogram），但"现在落在哪个桶"、
+// "跨越了多少个过期桶需要重置"、"聚合时该从哪个偏移读多少个桶"这套算法是完全一样的，
+// 抽出来嵌入到两个类型里，避免同一份跨度/偏移逻辑被复制维护两份。
+type rollingOffset struct {
+	size          int			// 滚动窗口大小
+	interval      time.Duration	// 间隔时间
+	offset        int			// 偏移位置，用来定位当前所在的桶
+	ignoreCurrent bool			// 是否忽略当前的桶
+	lastTime      time.Duration	// 最近时间，通过从lastTime开始到现在流逝的时间和interval，就能确定现在的offset
+}
+
+func newRollingOffset(size int, interval time.Duration) rollingOffset {
+	return rollingOffset{
+		size:     size,
+		interval: interval,
+		lastTime: timex.Now(),
+	}
+}
+
+// 计算跨度
+func (ro *rollingOffset) span() int {
+	offset := int(timex.Since(ro.lastTime) / ro.interval)
+	if 0 <= offset && offset < ro.size {
+		return offset
+	} else {
+		return ro.size
+	}
+}
+
+// 更新offset值，对每个过期的桶下标调用reset，再更新lastTime
+func (ro *rollingOffset) updateOffset(reset func(offset int)) {
+	span := ro.span()
+	if span <= 0 {
+		return
+	}
+
+	offset := ro.offset
+	start := offset + 1
+	steps := start + span
+	var remainder int
+	if steps > ro.size {
+		remainder = steps - ro.size
+		steps = ro.size
+	}
+
+	// reset expired buckets
+	for i := start; i < steps; i++ {
+		reset(i)
+	}
+	for i := 0; i < remainder; i++ {
+		reset(i)
+	}
+
+	ro.offset = (offset + span) % ro.size
+	ro.lastTime = timex.Now()
+}
+
+// spanOffsetAndDiff给Reduce/Merge这类聚合操作算出该从哪个偏移开始、读多少个未过期的桶；
+// diff<=0时表示没有可聚合的桶。
+func (ro *rollingOffset) spanOffsetAndDiff() (offset, diff int) {
+	span := ro.span()
+	// ignore current bucket, because of partial data
+	if span == 0 && ro.ignoreCurrent {
+		diff = ro.size - 1
+	} else {
+		diff = ro.size - span
+	}
+	if diff <= 0 {
+		return 0, 0
+	}
+
+	return (ro.offset + span + 1) % ro.size, diff
+}
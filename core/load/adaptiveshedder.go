@@ -20,6 +20,11 @@ const (
 	// using 1000m notation, 900m is like 80%, keep it as var for unit test
 	defaultCpuThreshold = 900
 	defaultMinRt        = float64(time.Second / time.Millisecond)
+	// default to p99, matches the tail-latency signal used by BBR/Sentinel
+	defaultLatencyQuantile = 0.99
+	minRtValue             = int64(1)
+	maxRtValue             = int64(time.Minute / time.Millisecond)
+	rtSignificantFigures   = 2
 	// moving average hyperparameter beta for calculating requests on the fly
 	flyingBeta      = 0.9
 	coolOffDuration = time.Second // 冷却期
@@ -49,15 +54,27 @@ type (
 	// 自适应限流器设置选项
 	ShedderOption func(opts *shedderOptions)
 
+	// OverloadCombiner根据当前的indicators判断系统是否过载，返回true时附带
+	// 判定为过载的那个指标（只用于日志，找不到具体指标时可以返回nil）。
+	// 默认的combiner是任意一个indicator过载就判定过载，也可以自己传一个
+	// combiner来实现比如"至少两个指标同时过载才丢请求"这样的组合策略。
+	OverloadCombiner func(indicators []LoadIndicator) (bool, LoadIndicator)
+
 	shedderOptions struct {
-		window       time.Duration
-		buckets      int
-		cpuThreshold int64
+		window          time.Duration
+		buckets         int
+		cpuThreshold    int64
+		latencyQuantile float64
+		indicators      []LoadIndicator
+		combiner        OverloadCombiner
 	}
 
 	// 自适应限流器定义
 	adaptiveShedder struct {
 		cpuThreshold    int64
+		latencyQuantile float64	// RT信号取的分位数，默认p99
+		indicators      []LoadIndicator	// 过载判断用的指标，cpu指标恒在第一位
+		combiner        OverloadCombiner	// 综合indicators判断是否过载，默认任意一个过载即过载
 		windows         int64	// 一秒时间内的桶
 		flying          int64	// 在途请求
 		avgFlying       float64	// 在途请求加权平均，avgFlying*flyingBeta + flying*(1-flyingBeta)
@@ -65,7 +82,7 @@ type (
 		dropTime        *syncx.AtomicDuration
 		droppedRecently *syncx.AtomicBool
 		passCounter     *collection.RollingWindow
-		rtCounter       *collection.RollingWindow
+		rtCounter       *collection.RollingHistogram
 	}
 )
 
@@ -80,23 +97,35 @@ func NewAdaptiveShedder(opts ...ShedderOption) Shedder {
 	}
 
 	options := shedderOptions{
-		window:       defaultWindow,
-		buckets:      defaultBuckets,
-		cpuThreshold: defaultCpuThreshold,
+		window:          defaultWindow,
+		buckets:         defaultBuckets,
+		cpuThreshold:    defaultCpuThreshold,
+		latencyQuantile: defaultLatencyQuantile,
 	}
 	for _, opt := range opts {
 		opt(&options)
 	}
 	bucketDuration := options.window / time.Duration(options.buckets)
+	indicators := make([]LoadIndicator, 0, len(options.indicators)+1)
+	indicators = append(indicators, NewCpuIndicator(options.cpuThreshold))
+	indicators = append(indicators, options.indicators...)
+	combiner := options.combiner
+	if combiner == nil {
+		combiner = anyOverloaded
+	}
 	return &adaptiveShedder{
 		cpuThreshold:    options.cpuThreshold,
+		latencyQuantile: options.latencyQuantile,
+		indicators:      indicators,
+		combiner:        combiner,
 		windows:         int64(time.Second / bucketDuration),
 		dropTime:        syncx.NewAtomicDuration(),
 		droppedRecently: syncx.NewAtomicBool(),
 		passCounter: collection.NewRollingWindow(options.buckets, bucketDuration,
 			collection.IgnoreCurrentBucket()),
-		rtCounter: collection.NewRollingWindow(options.buckets, bucketDuration,
-			collection.IgnoreCurrentBucket()),
+		rtCounter: collection.NewRollingHistogram(options.buckets, bucketDuration, minRtValue, maxRtValue,
+			collection.WithHistogramSignificantFigures(rtSignificantFigures),
+			collection.IgnoreCurrentHistogramBucket()),
 	}
 }
 
@@ -150,7 +179,7 @@ func (as *adaptiveShedder) maxFlight() int64 {
 	// maxQPS = maxPASS * windows
 	// minRT = min average response time in milliseconds
 	// maxQPS * minRT / milliseconds_per_second
-	return int64(math.Max(1, float64(as.maxPass()*as.windows)*(as.minRt()/1e3)))
+	return int64(math.Max(1, float64(as.maxPass()*as.windows)*(as.latencyRt()/1e3)))
 }
 
 // 桶内最大的数
@@ -166,35 +195,31 @@ func (as *adaptiveShedder) maxPass() int64 {
 	return int64(result)
 }
 
-// 最小rt
-func (as *adaptiveShedder) minRt() float64 {
-	var result = defaultMinRt
-
-	as.rtCounter.Reduce(func(b *collection.Bucket) {
-		if b.Count <= 0 {
-			return
-		}
-
-		avg := math.Round(b.Sum / float64(b.Count))
-		if avg < result {
-			result = avg
-		}
-	})
+// latencyRt取RT直方图的P99（或自定义分位数），作为尾延迟信号。
+// 相比旧版minRt取各桶平均值的最小值，分位数是从真实分布里算出来的，
+// 在突发流量下不会被平均值"拉低"，从而让maxFlight的估计更准。
+func (as *adaptiveShedder) latencyRt() float64 {
+	rt := float64(as.rtCounter.Quantile(as.latencyQuantile))
+	if rt <= 0 {
+		return defaultMinRt
+	}
 
-	return result
+	return rt
 }
 
 // 是否应该拦截
 func (as *adaptiveShedder) shouldDrop() bool {
-	if as.systemOverloaded() || as.stillHot() {
+	overloaded, indicator := as.overloaded()
+	if overloaded || as.stillHot() {
 		if as.highThru() {
 			flying := atomic.LoadInt64(&as.flying)
 			as.avgFlyingLock.Lock()
 			avgFlying := as.avgFlying
 			as.avgFlyingLock.Unlock()
 			msg := fmt.Sprintf(
-				"dropreq, cpu: %d, maxPass: %d, minRt: %.2f, hot: %t, flying: %d, avgFlying: %.2f",
-				stat.CpuUsage(), as.maxPass(), as.minRt(), as.stillHot(), flying, avgFlying)
+				"dropreq, %s: %.2f, maxPass: %d, latencyRt(p%.0f): %.2f, hot: %t, flying: %d, avgFlying: %.2f",
+				indicatorName(indicator), indicatorValue(indicator), as.maxPass(), as.latencyQuantile*100,
+				as.latencyRt(), as.stillHot(), flying, avgFlying)
 			logx.Error(msg)
 			stat.Report(msg)
 			return true
@@ -204,6 +229,53 @@ func (as *adaptiveShedder) shouldDrop() bool {
 	return false
 }
 
+// overloaded把as.indicators交给combiner去综合判断；默认combiner是anyOverloaded
+func (as *adaptiveShedder) overloaded() (bool, LoadIndicator) {
+	return as.combiner(as.indicators)
+}
+
+// anyOverloaded是默认的OverloadCombiner：依次检查indicators，返回第一个过载的指标；
+// 都没过载时返回false, nil
+func anyOverloaded(indicators []LoadIndicator) (bool, LoadIndicator) {
+	for _, indicator := range indicators {
+		if indicator.Overloaded() {
+			return true, indicator
+		}
+	}
+
+	return false, nil
+}
+
+// Stats返回当前各个LoadIndicator的快照，供stat.Report之类的上层统计使用
+func (as *adaptiveShedder) Stats() []IndicatorStat {
+	stats := make([]IndicatorStat, 0, len(as.indicators))
+	for _, indicator := range as.indicators {
+		stats = append(stats, IndicatorStat{
+			Name:  indicator.Name(),
+			Value: indicator.Value(),
+		})
+	}
+
+	return stats
+}
+
+// indicatorName在没有触发过载的情况下（比如只是stillHot）兜底返回cpu，保持和旧版日志字段一致
+func indicatorName(indicator LoadIndicator) string {
+	if indicator == nil {
+		return "cpu"
+	}
+
+	return indicator.Name()
+}
+
+func indicatorValue(indicator LoadIndicator) float64 {
+	if indicator == nil {
+		return float64(stat.CpuUsage())
+	}
+
+	return indicator.Value()
+}
+
 func (as *adaptiveShedder) stillHot() bool {
 	if !as.droppedRecently.True() {
 		return false
@@ -222,11 +294,6 @@ func (as *adaptiveShedder) stillHot() bool {
 	return hot
 }
 
-// cpu使用率是否超过cpuThreshold阈值
-func (as *adaptiveShedder) systemOverloaded() bool {
-	return systemOverloadChecker(as.cpuThreshold)
-}
-
 // 设置桶数
 func WithBuckets(buckets int) ShedderOption {
 	return func(opts *shedderOptions) {
@@ -248,6 +315,30 @@ func WithWindow(window time.Duration) ShedderOption {
 	}
 }
 
+// WithLatencyQuantile 设置RT信号取的分位数，默认p99(0.99)
+func WithLatencyQuantile(q float64) ShedderOption {
+	return func(opts *shedderOptions) {
+		opts.latencyQuantile = q
+	}
+}
+
+// WithIndicators 给限流器追加额外的LoadIndicator（内存、goroutine数、fd数等），
+// 默认只要任意一个指标过载就会触发限流（除非用WithOverloadCombiner换了别的组合逻辑）；
+// cpu指标始终内置，不受这个选项影响。
+func WithIndicators(indicators ...LoadIndicator) ShedderOption {
+	return func(opts *shedderOptions) {
+		opts.indicators = append(opts.indicators, indicators...)
+	}
+}
+
+// WithOverloadCombiner 用调用方自己的逻辑替换默认的"任意一个indicator过载即过载"，
+// 比如要求至少两个指标同时过载才判定为过载。
+func WithOverloadCombiner(combiner OverloadCombiner) ShedderOption {
+	return func(opts *shedderOptions) {
+		opts.combiner = combiner
+	}
+}
+
 type promise struct {
 	start   time.Duration
 	shedder *adaptiveShedder
@@ -260,6 +351,6 @@ func (p *promise) Fail() {
 func (p *promise) Pass() {
 	rt := float64(timex.Since(p.start)) / float64(time.Millisecond)
 	p.shedder.addFlying(-1)
-	p.shedder.rtCounter.Add(math.Ceil(rt))
+	p.shedder.rtCounter.Record(int64(math.Ceil(rt)))
 	p.shedder.passCounter.Add(1)
 }
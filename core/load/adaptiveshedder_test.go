@@ -0,0 +1,76 @@
+package load
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeIndicator struct {
+	name       string
+	overloaded bool
+}
+
+func (i *fakeIndicator) Overloaded() bool {
+	return i.overloaded
+}
+
+func (i *fakeIndicator) Name() string {
+	return i.name
+}
+
+func (i *fakeIndicator) Value() float64 {
+	return 0
+}
+
+func TestAdaptiveShedderDefaultCombinerIsAnyOverloaded(t *testing.T) {
+	old := systemOverloadChecker
+	defer func() {
+		systemOverloadChecker = old
+	}()
+	systemOverloadChecker = func(cpuThreshold int64) bool {
+		return false
+	}
+
+	shedder := NewAdaptiveShedder(WithIndicators(&fakeIndicator{name: "fake"})).(*adaptiveShedder)
+
+	overloaded, indicator := shedder.overloaded()
+	assert.False(t, overloaded)
+	assert.Nil(t, indicator)
+
+	shedder.indicators[len(shedder.indicators)-1].(*fakeIndicator).overloaded = true
+	overloaded, indicator = shedder.overloaded()
+	assert.True(t, overloaded)
+	assert.Equal(t, "fake", indicator.Name())
+}
+
+func TestAdaptiveShedderWithOverloadCombiner(t *testing.T) {
+	old := systemOverloadChecker
+	defer func() {
+		systemOverloadChecker = old
+	}()
+	systemOverloadChecker = func(cpuThreshold int64) bool {
+		return false
+	}
+
+	// 自定义combiner：至少两个指标同时过载才判定为过载
+	atLeastTwo := func(indicators []LoadIndicator) (bool, LoadIndicator) {
+		var count int
+		var last LoadIndicator
+		for _, indicator := range indicators {
+			if indicator.Overloaded() {
+				count++
+				last = indicator
+			}
+		}
+		return count >= 2, last
+	}
+
+	shedder := NewAdaptiveShedder(
+		WithIndicators(&fakeIndicator{name: "fake1", overloaded: true}),
+		WithOverloadCombiner(atLeastTwo),
+	).(*adaptiveShedder)
+
+	overloaded, _ := shedder.overloaded()
+	assert.False(t, overloaded, "cpu indicator is not overloaded, only one of two is")
+}
@@ -0,0 +1,195 @@
+package load
+
+import (
+	"math"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/lang"
+	"github.com/tal-tech/go-zero/core/stat"
+)
+
+const (
+	// memEwmaAlpha是内存占用EWMA的平滑系数，GC之后RSS/heap会抖动得很厉害，
+	// EWMA能把这种瞬时抖动过滤掉，只留下趋势性的增长。
+	memEwmaAlpha           = 0.3
+	defaultMemoryThreshold = 0.9 // 相对于memoryCap的占用比例
+	defaultGoroutineLimit  = 20000
+	// indicatorSampleInterval是mem/fd这类采样开销较大的指标后台刷新的节奏，
+	// Overloaded/Value只读取上一次采样的缓存值，不会在请求路径上同步去做这些开销。
+	indicatorSampleInterval = time.Second
+)
+
+type (
+	// LoadIndicator是shouldDrop用来判断"系统是否过载"的最小抽象，
+	// 内置了cpu/内存/goroutine数/fd数四种实现，也可以自己实现这个接口接入别的信号
+	// （比如下游依赖的错误率、队列深度等）。
+	LoadIndicator interface {
+		// Overloaded判断这个指标当前是否过载
+		Overloaded() bool
+		// Name返回指标名字，用于dropreq日志和Stats()
+		Name() string
+		// Value返回指标当前的值，用于dropreq日志和Stats()
+		Value() float64
+	}
+
+	// IndicatorStat是某个LoadIndicator在某一时刻的快照
+	IndicatorStat struct {
+		Name  string
+		Value float64
+	}
+
+	cpuIndicator struct {
+		threshold int64
+	}
+
+	// memoryIndicator用EWMA平滑后的heap占用字节数，与memoryCap的比例来判断是否过载
+	memoryIndicator struct {
+		lock      sync.Mutex
+		ewma      float64
+		memoryCap uint64
+		threshold float64
+		done      chan lang.PlaceholderType
+	}
+
+	goroutineIndicator struct {
+		limit int64
+	}
+)
+
+// NewCpuIndicator用cpuThreshold(千分位，900表示90%)创建一个cpu过载指标，
+// 和以前adaptiveShedder内置的cpu检查行为完全一致。
+func NewCpuIndicator(cpuThreshold int64) LoadIndicator {
+	return &cpuIndicator{threshold: cpuThreshold}
+}
+
+func (i *cpuIndicator) Overloaded() bool {
+	return systemOverloadChecker(i.threshold)
+}
+
+func (i *cpuIndicator) Name() string {
+	return "cpu"
+}
+
+func (i *cpuIndicator) Value() float64 {
+	return float64(stat.CpuUsage())
+}
+
+// NewMemoryIndicator创建一个内存过载指标，memoryCap是用来换算占用比例的内存上限，
+// 不传（0）时优先用debug.SetMemoryLimit(-1)读到的GOMEMLIMIT作为默认上限，
+// 如果进程没有设置GOMEMLIMIT（拿到的是runtime默认的math.MaxInt64），
+// 则保持0——此时Value永远是0、不会触发过载，调用方需要显式传一个容器内存limit。
+// threshold是占用比例的阈值(0~1)。
+// runtime.ReadMemStats是一次全量扫描，很贵，所以真正的采样在后台ticker里异步进行，
+// Overloaded/Value只读取缓存下来的EWMA值，不占用请求路径的时间。
+func NewMemoryIndicator(memoryCap uint64, threshold float64) LoadIndicator {
+	if threshold <= 0 {
+		threshold = defaultMemoryThreshold
+	}
+	if memoryCap == 0 {
+		memoryCap = memoryLimitFromRuntime()
+	}
+
+	i := &memoryIndicator{
+		memoryCap: memoryCap,
+		threshold: threshold,
+		done:      make(chan lang.PlaceholderType),
+	}
+	go i.sampleLoop(indicatorSampleInterval)
+
+	return i
+}
+
+// Close停止后台采样，mirrors LRUCache.Close()；不调用Close的memoryIndicator
+// 会让后台goroutine和ticker一直跑到进程退出，测试和短生命周期的调用方都应该
+// 在用完之后调Close。
+func (i *memoryIndicator) Close() {
+	close(i.done)
+}
+
+// memoryLimitFromRuntime读取GOMEMLIMIT对应的runtime软内存上限，
+// 进程没有设置GOMEMLIMIT时返回0，交给调用方决定用什么兜底。
+func memoryLimitFromRuntime() uint64 {
+	limit := debug.SetMemoryLimit(-1)
+	if limit <= 0 || limit == math.MaxInt64 {
+		return 0
+	}
+
+	return uint64(limit)
+}
+
+func (i *memoryIndicator) Overloaded() bool {
+	return i.Value() >= i.threshold
+}
+
+func (i *memoryIndicator) Name() string {
+	return "mem"
+}
+
+// Value返回的是后台采样、EWMA平滑后的heap占用相对于memoryCap的比例
+func (i *memoryIndicator) Value() float64 {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.ewma
+}
+
+func (i *memoryIndicator) sampleLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	i.sample()
+	for {
+		select {
+		case <-ticker.C:
+			i.sample()
+		case <-i.done:
+			return
+		}
+	}
+}
+
+// sample做一次真正有开销的ReadMemStats采样，更新EWMA；只应该被后台ticker调用，
+// 不能挂在Value/Overloaded上，否则请求路径上每次判断都要扫一遍MemStats，
+// 决定丢请求时的日志又会把同一次采样重复叠加进EWMA，导致平滑被做两次。
+func (i *memoryIndicator) sample() {
+	if i.memoryCap == 0 {
+		return
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	usage := float64(ms.HeapAlloc) / float64(i.memoryCap)
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.ewma == 0 {
+		i.ewma = usage
+	} else {
+		i.ewma = i.ewma*(1-memEwmaAlpha) + usage*memEwmaAlpha
+	}
+}
+
+// NewGoroutineIndicator创建一个goroutine数量过载指标
+func NewGoroutineIndicator(limit int64) LoadIndicator {
+	if limit <= 0 {
+		limit = defaultGoroutineLimit
+	}
+
+	return &goroutineIndicator{limit: limit}
+}
+
+func (i *goroutineIndicator) Overloaded() bool {
+	return int64(runtime.NumGoroutine()) >= i.limit
+}
+
+func (i *goroutineIndicator) Name() string {
+	return "goroutine"
+}
+
+func (i *goroutineIndicator) Value() float64 {
+	return float64(runtime.NumGoroutine())
+}
@@ -0,0 +1,91 @@
+package load
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/tal-tech/go-zero/core/lang"
+)
+
+const defaultFdThreshold = 0.8
+
+// fdIndicator通过/proc/self/fd里的条目数和RLIMIT_NOFILE的比例来判断fd是否紧张，
+// 只在Linux下才有/proc文件系统，其它平台用indicator_fd_other.go里的空实现。
+// os.ReadDir("/proc/self/fd")不是一个便宜的调用，所以和memoryIndicator一样，
+// 真正的采样在后台ticker里做，Overloaded/Value只读缓存值。
+type fdIndicator struct {
+	lock      sync.Mutex
+	value     float64
+	threshold float64
+	done      chan lang.PlaceholderType
+}
+
+// NewFDIndicator创建一个文件描述符占用指标，threshold是相对于RLIMIT_NOFILE的比例阈值(0~1)
+func NewFDIndicator(threshold float64) LoadIndicator {
+	if threshold <= 0 {
+		threshold = defaultFdThreshold
+	}
+
+	i := &fdIndicator{
+		threshold: threshold,
+		done:      make(chan lang.PlaceholderType),
+	}
+	go i.sampleLoop(indicatorSampleInterval)
+
+	return i
+}
+
+// Close停止后台采样，mirrors memoryIndicator.Close()/LRUCache.Close()
+func (i *fdIndicator) Close() {
+	close(i.done)
+}
+
+func (i *fdIndicator) Overloaded() bool {
+	return i.Value() >= i.threshold
+}
+
+func (i *fdIndicator) Name() string {
+	return "fd"
+}
+
+func (i *fdIndicator) Value() float64 {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.value
+}
+
+func (i *fdIndicator) sampleLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	i.sample()
+	for {
+		select {
+		case <-ticker.C:
+			i.sample()
+		case <-i.done:
+			return
+		}
+	}
+}
+
+func (i *fdIndicator) sample() {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil || rlimit.Cur == 0 {
+		return
+	}
+
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return
+	}
+
+	value := float64(len(entries)) / float64(rlimit.Cur)
+
+	i.lock.Lock()
+	i.value = value
+	i.lock.Unlock()
+}
@@ -0,0 +1,17 @@
+package load
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFDIndicatorSampleUpdatesValue(t *testing.T) {
+	indicator := NewFDIndicator(1e-9).(*fdIndicator)
+	defer indicator.Close()
+	assert.Equal(t, "fd", indicator.Name())
+
+	indicator.sample()
+	assert.True(t, indicator.Value() > 0)
+	assert.True(t, indicator.Overloaded())
+}
@@ -0,0 +1,29 @@
+// +build !linux
+
+package load
+
+// fdIndicator在非Linux平台上没有一个可移植的办法数出当前打开的fd数，
+// 所以这里是一个永远不会触发过载的空实现，避免在其它平台上编不过或者误报。
+type fdIndicator struct{}
+
+// NewFDIndicator创建一个文件描述符占用指标，非Linux平台下恒不过载
+func NewFDIndicator(threshold float64) LoadIndicator {
+	return &fdIndicator{}
+}
+
+func (i *fdIndicator) Overloaded() bool {
+	return false
+}
+
+func (i *fdIndicator) Name() string {
+	return "fd"
+}
+
+func (i *fdIndicator) Value() float64 {
+	return 0
+}
+
+// Close在非Linux平台上没有后台goroutine可停，留空只是为了和Linux下的
+// fdIndicator保持同样的方法集
+func (i *fdIndicator) Close() {
+}
@@ -0,0 +1,72 @@
+package load
+
+import (
+	"math"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCpuIndicator(t *testing.T) {
+	old := systemOverloadChecker
+	defer func() {
+		systemOverloadChecker = old
+	}()
+	systemOverloadChecker = func(cpuThreshold int64) bool {
+		return cpuThreshold == 500
+	}
+
+	indicator := NewCpuIndicator(500)
+	assert.Equal(t, "cpu", indicator.Name())
+	assert.True(t, indicator.Overloaded())
+}
+
+func TestMemoryIndicatorSampleUpdatesValue(t *testing.T) {
+	indicator := NewMemoryIndicator(1, 0).(*memoryIndicator)
+	defer indicator.Close()
+	assert.Equal(t, "mem", indicator.Name())
+
+	indicator.sample()
+	assert.True(t, indicator.Value() > 0)
+	assert.True(t, indicator.Overloaded())
+}
+
+func TestMemoryIndicatorValueDoesNotResample(t *testing.T) {
+	indicator := NewMemoryIndicator(1, 0).(*memoryIndicator)
+	defer indicator.Close()
+	indicator.sample()
+	first := indicator.Value()
+	// 多次读Value不应该触发新的采样/重复EWMA叠加，值应该保持不变
+	second := indicator.Value()
+	assert.Equal(t, first, second)
+}
+
+func TestMemoryIndicatorInertWithoutCap(t *testing.T) {
+	indicator := NewMemoryIndicator(0, 0).(*memoryIndicator)
+	defer indicator.Close()
+	if indicator.memoryCap != 0 {
+		t.Skip("GOMEMLIMIT is configured in this environment, indicator is not inert")
+	}
+
+	indicator.sample()
+	assert.Equal(t, float64(0), indicator.Value())
+	assert.False(t, indicator.Overloaded())
+}
+
+func TestMemoryLimitFromRuntime(t *testing.T) {
+	old := debug.SetMemoryLimit(64 << 20)
+	defer debug.SetMemoryLimit(old)
+
+	assert.Equal(t, uint64(64<<20), memoryLimitFromRuntime())
+
+	debug.SetMemoryLimit(math.MaxInt64)
+	assert.Equal(t, uint64(0), memoryLimitFromRuntime())
+}
+
+func TestGoroutineIndicator(t *testing.T) {
+	indicator := NewGoroutineIndicator(1)
+	assert.Equal(t, "goroutine", indicator.Name())
+	assert.True(t, indicator.Value() >= 1)
+	assert.True(t, indicator.Overloaded())
+}
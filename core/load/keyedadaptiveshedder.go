@@ -0,0 +1,80 @@
+package load
+
+import (
+	"context"
+
+	"github.com/tal-tech/go-zero/core/collection"
+)
+
+const defaultKeyedShedderCapacity = 1000
+
+type (
+	// KeyFunc 从ctx提取一个用于区分租户/路由/调用方的key
+	KeyFunc func(ctx context.Context) string
+
+	// KeyedShedder 和Shedder类似，区别在于Allow需要一个ctx，用来定位调用方落在哪个key上
+	KeyedShedder interface {
+		Allow(ctx context.Context) (Promise, error)
+	}
+
+	// KeyedShedderOption 给KeyedAdaptiveShedder整体和每个per-key shedder复用的选项
+	KeyedShedderOption func(opts *keyedShedderOptions)
+
+	keyedShedderOptions struct {
+		capacity      int
+		shedderOpts   []ShedderOption
+	}
+
+	// keyedAdaptiveShedder 在一个LRU里维护每个key各自的adaptiveShedder，
+	// 这样可以按租户/路由/调用方单独限流，而不是所有请求共用一个全局窗口；
+	// 全局CPU过载依然是先决条件，单个key的shedder只决定"这个key自己的那份流量"该不该丢。
+	keyedAdaptiveShedder struct {
+		keyFn       KeyFunc
+		cache       *collection.LRUCache
+		shedderOpts []ShedderOption
+	}
+)
+
+// NewKeyedAdaptiveShedder 创建一个按key区分的自适应限流器，
+// 冷门key（长时间没有流量）会从LRU里被淘汰，对应的shedder随之释放。
+func NewKeyedAdaptiveShedder(keyFn KeyFunc, opts ...KeyedShedderOption) KeyedShedder {
+	options := keyedShedderOptions{
+		capacity: defaultKeyedShedderCapacity,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &keyedAdaptiveShedder{
+		keyFn:       keyFn,
+		cache:       collection.New(options.capacity),
+		shedderOpts: options.shedderOpts,
+	}
+}
+
+// Allow 按ctx提取的key找到（或创建）对应的shedder，再交给它判断
+func (ks *keyedAdaptiveShedder) Allow(ctx context.Context) (Promise, error) {
+	return ks.shedderFor(ks.keyFn(ctx)).Allow()
+}
+
+func (ks *keyedAdaptiveShedder) shedderFor(key string) Shedder {
+	v, _ := ks.cache.GetOrCreate(key, func() interface{} {
+		return NewAdaptiveShedder(ks.shedderOpts...)
+	})
+
+	return v.(Shedder)
+}
+
+// WithKeyedCapacity 设置维护per-key shedder的LRU容量，默认1000
+func WithKeyedCapacity(capacity int) KeyedShedderOption {
+	return func(opts *keyedShedderOptions) {
+		opts.capacity = capacity
+	}
+}
+
+// WithKeyedShedderOptions 设置创建每个per-key shedder时使用的选项
+func WithKeyedShedderOptions(shedderOpts ...ShedderOption) KeyedShedderOption {
+	return func(opts *keyedShedderOptions) {
+		opts.shedderOpts = shedderOpts
+	}
+}
@@ -0,0 +1,43 @@
+package load
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedAdaptiveShedderShedderForConcurrent(t *testing.T) {
+	ks := NewKeyedAdaptiveShedder(func(ctx context.Context) string {
+		return "same-key"
+	}).(*keyedAdaptiveShedder)
+
+	const routines = 200
+	shedders := make([]Shedder, routines)
+	var wg sync.WaitGroup
+	wg.Add(routines)
+	for i := 0; i < routines; i++ {
+		i := i
+		go func() {
+			defer wg.Done()
+			shedders[i] = ks.shedderFor("same-key")
+		}()
+	}
+	wg.Wait()
+
+	for i := 1; i < routines; i++ {
+		assert.Same(t, shedders[0], shedders[i])
+	}
+}
+
+func TestKeyedAdaptiveShedderAllow(t *testing.T) {
+	ks := NewKeyedAdaptiveShedder(func(ctx context.Context) string {
+		return "tenant-a"
+	})
+
+	p, err := ks.Allow(context.Background())
+	assert.Nil(t, err)
+	assert.NotNil(t, p)
+	p.Pass()
+}
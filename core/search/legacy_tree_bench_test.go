@@ -0,0 +1,200 @@
+package search
+
+import (
+	"testing"
+)
+
+// legacyTree是重写前的map-based实现，只在这里留一份用来和现在的压缩前缀树
+// 做benchmark对比，证明chunk0-3确实是一次性能提升而不是单方面的自我衡量。
+// 真正的产品代码已经不再维护这份实现，这里只保留跑分需要的最小子集。
+type (
+	legacyInnerResult struct {
+		key   string
+		value string
+		named bool
+		found bool
+	}
+
+	legacyNode struct {
+		item     interface{}
+		children [2]map[string]*legacyNode
+	}
+
+	legacyTree struct {
+		root *legacyNode
+	}
+)
+
+func newLegacyTree() *legacyTree {
+	return &legacyTree{root: newLegacyNode(nil)}
+}
+
+func (t *legacyTree) Add(route string, item interface{}) error {
+	if len(route) == 0 || route[0] != slash {
+		return ErrNotFromRoot
+	}
+	if item == nil {
+		return ErrEmptyItem
+	}
+
+	return legacyAdd(t.root, route[1:], item)
+}
+
+func (t *legacyTree) Search(route string) (Result, bool) {
+	if len(route) == 0 || route[0] != slash {
+		return NotFound, false
+	}
+
+	var result Result
+	ok := t.next(t.root, route[1:], &result)
+	return result, ok
+}
+
+func (t *legacyTree) next(n *legacyNode, route string, result *Result) bool {
+	if len(route) == 0 && n.item != nil {
+		result.Item = n.item
+		return true
+	}
+
+	for i := range route {
+		if route[i] == slash {
+			token := route[:i]
+			for _, children := range n.children {
+				for k, v := range children {
+					if r := legacyMatch(k, token); r.found {
+						if t.next(v, route[i+1:], result) {
+							if r.named {
+								addParam(result, r.key, r.value)
+							}
+							return true
+						}
+					}
+				}
+			}
+
+			return false
+		}
+	}
+
+	for _, children := range n.children {
+		for k, v := range children {
+			if r := legacyMatch(k, route); r.found && v.item != nil {
+				result.Item = v.item
+				if r.named {
+					addParam(result, r.key, r.value)
+				}
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (nd *legacyNode) getChildren(route string) map[string]*legacyNode {
+	if len(route) > 0 && route[0] == colon {
+		return nd.children[1]
+	}
+
+	return nd.children[0]
+}
+
+func legacyAdd(nd *legacyNode, route string, item interface{}) error {
+	if len(route) == 0 {
+		if nd.item != nil {
+			return ErrDupItem
+		}
+
+		nd.item = item
+		return nil
+	}
+
+	if route[0] == slash {
+		return ErrDupSlash
+	}
+
+	for i := range route {
+		if route[i] == slash {
+			token := route[:i]
+			children := nd.getChildren(token)
+			if child, ok := children[token]; ok {
+				if child != nil {
+					return legacyAdd(child, route[i+1:], item)
+				}
+				return ErrInvalidState
+			}
+
+			child := newLegacyNode(nil)
+			children[token] = child
+			return legacyAdd(child, route[i+1:], item)
+		}
+	}
+
+	children := nd.getChildren(route)
+	if child, ok := children[route]; ok {
+		if child.item != nil {
+			return ErrDupItem
+		}
+
+		child.item = item
+	} else {
+		children[route] = newLegacyNode(item)
+	}
+
+	return nil
+}
+
+func legacyMatch(pat, token string) legacyInnerResult {
+	if pat[0] == colon {
+		return legacyInnerResult{
+			key:   pat[1:],
+			value: token,
+			named: true,
+			found: true,
+		}
+	}
+
+	return legacyInnerResult{found: pat == token}
+}
+
+func newLegacyNode(item interface{}) *legacyNode {
+	return &legacyNode{
+		item: item,
+		children: [2]map[string]*legacyNode{
+			make(map[string]*legacyNode),
+			make(map[string]*legacyNode),
+		},
+	}
+}
+
+func BenchmarkLegacyTreeSearch(b *testing.B) {
+	templates, paths := benchRoutes(1000)
+	tree := newLegacyTree()
+	for _, route := range templates {
+		if err := tree.Add(route, route); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		path := paths[i%len(paths)]
+		if _, ok := tree.Search(path); !ok {
+			b.Fatal("not found")
+		}
+	}
+}
+
+func BenchmarkLegacyTreeAdd(b *testing.B) {
+	templates, _ := benchRoutes(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree := newLegacyTree()
+		for _, route := range templates {
+			if err := tree.Add(route, route); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -1,10 +1,22 @@
-// 使用二叉查找树来实现路由查找
+// 使用压缩前缀树(radix tree)来实现路由查找。
+// 原来的实现每一层都要分配两个map[string]*node，Search时要把某一层下的
+// 所有孩子节点都遍历一遍去做字符串匹配，对于大规模路由表（比如上千条路由）
+// 来说既浪费内存又浪费CPU。压缩前缀树按最长公共前缀共享节点，
+// 每个节点最多只有三类孩子：按首字节排序、可二分查找的静态边，
+// 唯一的一条:param边（捕获到下一个'/'为止），唯一的一条*catchall边
+// （捕获剩余的全部内容），匹配时优先走静态边，其次走param边，最后走catchall边，
+// 从而保证更具体的路由总是优先命中。
 package search
 
-import "errors"
+import (
+	"errors"
+	"sort"
+	"strings"
+)
 
 const (
 	colon = ':'
+	star  = '*'
 	slash = '/'
 )
 
@@ -19,26 +31,27 @@ var (
 )
 
 type (
-	// 内部使用结构体
-	innerResult struct {
-		key   string
-		value string
-		named bool
-		found bool
-	}
+	// TreeOption 树的选项函数
+	TreeOption func(t *Tree)
 
-	// 节点
+	// 压缩前缀树的节点，path是这个节点对应的那条边上的byte前缀。
+	// priority只影响Walk的遍历顺序，不参与Search的匹配逻辑——
+	// 静态/param/catchall谁更具体由树的结构本身决定。
 	node struct {
-		// 元素, handler
-		item     interface{}
-		// 孩子节点, key 为'/'分割的字符串， value为孩子节点
-		// 0 存储一般节点/ 1 存储带冒号节点:
-		children [2]map[string]*node
+		path      string
+		priority  int
+		paramName string // 当这个node是param/catchall节点时，捕获到的参数名
+		indices   string // 静态孩子首字节组成的索引，和statics一一对应，按字节有序
+		statics   []*node
+		param     *node
+		catchAll  *node
+		item      interface{}
 	}
 
 	// 树
 	Tree struct {
-		root *node	// 根节点
+		root            *node
+		caseInsensitive bool
 	}
 
 	// 外部使用结构体
@@ -48,16 +61,33 @@ type (
 	}
 )
 
-// 创建树
-func NewTree() *Tree {
-	return &Tree{
-		root: newNode(nil),
+// NewTree 创建树
+func NewTree(opts ...TreeOption) *Tree {
+	t := &Tree{
+		root: &node{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// CaseInsensitive 让路由的添加和查找都忽略大小写
+func CaseInsensitive() TreeOption {
+	return func(t *Tree) {
+		t.caseInsensitive = true
 	}
 }
 
-// 添加一个路径
+// Add 添加一个路径，优先级默认为0
 func (t *Tree) Add(route string, item interface{}) error {
-	// 路径不为空，且必须以‘/’开头
+	return t.AddWithPriority(route, item, 0)
+}
+
+// AddWithPriority 添加一个路径，priority用来在具体程度相同的路由之间
+// 决定谁更优先（体现在Walk的遍历顺序上），priority越大越靠前。
+func (t *Tree) AddWithPriority(route string, item interface{}, priority int) error {
 	if len(route) == 0 || route[0] != slash {
 		return ErrNotFromRoot
 	}
@@ -66,118 +96,253 @@ func (t *Tree) Add(route string, item interface{}) error {
 		return ErrEmptyItem
 	}
 
-	return add(t.root, route[1:], item)
+	if strings.Contains(route, "//") {
+		return ErrDupSlash
+	}
+
+	if t.caseInsensitive {
+		route = asciiLower(route)
+	}
+
+	return t.root.addRoute(route[1:], item, priority)
 }
 
-// 匹配搜索路径
+// Search 匹配搜索路径。大小写无关模式下，只有用来匹配静态边的那份拷贝会被
+// 转成小写，真正捕获进Params里的:param/*catchall的值仍然来自原始的route，
+// 不会被悄悄改写大小写。
 func (t *Tree) Search(route string) (Result, bool) {
 	if len(route) == 0 || route[0] != slash {
 		return NotFound, false
 	}
 
+	matchRoute := route
+	if t.caseInsensitive {
+		matchRoute = asciiLower(route)
+	}
+
 	var result Result
-	ok := t.next(t.root, route[1:], &result)
+	ok := t.root.search(matchRoute[1:], route[1:], &result)
 	return result, ok
 }
 
-func (t *Tree) next(n *node, route string, result *Result) bool {
-	if len(route) == 0 && n.item != nil {
-		result.Item = n.item
-		return true
+// Walk 枚举树中所有注册过的路由，fn返回error时立即停止遍历并把error透传出去；
+// 同一节点下的静态孩子按priority从高到低遍历。
+func (t *Tree) Walk(fn func(route string, item interface{}) error) error {
+	return t.root.walk("/", fn)
+}
+
+func (nd *node) walk(prefix string, fn func(route string, item interface{}) error) error {
+	route := prefix + nd.path
+	if nd.item != nil {
+		if err := fn(route, nd.item); err != nil {
+			return err
+		}
 	}
 
-	for i := range route {
-		if route[i] == slash {
-			token := route[:i]
-			for _, children := range n.children {
-				for k, v := range children {
-					if r := match(k, token); r.found {
-						if t.next(v, route[i+1:], result) {
-							if r.named {
-								addParam(result, r.key, r.value)
-							}
-
-							return true
-						}
-					}
-				}
-			}
+	children := make([]*node, len(nd.statics))
+	copy(children, nd.statics)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].priority > children[j].priority
+	})
+	for _, child := range children {
+		if err := child.walk(route, fn); err != nil {
+			return err
+		}
+	}
 
-			return false
+	if nd.param != nil {
+		if err := nd.param.walk(route+":"+nd.param.paramName, fn); err != nil {
+			return err
+		}
+	}
+	if nd.catchAll != nil {
+		if err := fn(route+"*"+nd.catchAll.paramName, nd.catchAll.item); err != nil {
+			return err
 		}
 	}
 
-	for _, children := range n.children {
-		for k, v := range children {
-			if r := match(k, route); r.found && v.item != nil {
-				result.Item = v.item
-				if r.named {
-					addParam(result, r.key, r.value)
-				}
+	return nil
+}
 
-				return true
+// addRoute 把route插入以nd为根的子树
+func (nd *node) addRoute(route string, item interface{}, priority int) error {
+	n := nd
+	for {
+		i := longestCommonPrefix(route, n.path)
+
+		// 需要在n.path中间拆分出一个新的静态边
+		if i < len(n.path) {
+			child := &node{
+				path:     n.path[i:],
+				priority: n.priority,
+				indices:  n.indices,
+				statics:  n.statics,
+				param:    n.param,
+				catchAll: n.catchAll,
+				item:     n.item,
 			}
+
+			n.statics = []*node{child}
+			n.indices = string(n.path[i])
+			n.path = n.path[:i]
+			n.item = nil
+			n.param = nil
+			n.catchAll = nil
 		}
-	}
 
-	return false
-}
+		n.priority += priority
+
+		if i >= len(route) {
+			if n.item != nil {
+				return ErrDupItem
+			}
+			n.item = item
+			return nil
+		}
+
+		route = route[i:]
+		c := route[0]
+
+		if c == colon || c == star {
+			return n.insertChild(route, item, priority)
+		}
+
+		if idx := indexOfSorted(n.indices, c); idx >= 0 {
+			n = n.statics[idx]
+			continue
+		}
 
-// 获取子节点
-func (nd *node) getChildren(route string) map[string]*node {
-	if len(route) > 0 && route[0] == colon {
-		return nd.children[1]
-	} else {
-		return nd.children[0]
+		child := &node{}
+		n.indices, n.statics = insertSorted(n.indices, n.statics, c, child)
+		return child.insertChild(route, item, priority)
 	}
 }
 
-func add(nd *node, route string, item interface{}) error {
-	if len(route) == 0 {	// 处理最后一个字符是'/'的情况
+// insertChild处理route里剩下的内容，包括可能出现的:param/*catchall。
+// 调用前提：nd自己还没有设置path（要么是全新节点，要么是route[0]为:或*的既有节点）。
+func (nd *node) insertChild(route string, item interface{}, priority int) error {
+	wildcard, i := findWildcard(route)
+	if i < 0 {
+		nd.path = route
 		if nd.item != nil {
 			return ErrDupItem
 		}
-
 		nd.item = item
+		nd.priority += priority
 		return nil
 	}
 
-	if route[0] == slash {
-		return ErrDupSlash
+	if i > 0 {
+		nd.path = route[:i]
+		return nd.insertChild(route[i:], item, priority)
 	}
 
-	// 递归处理
-	for i := range route {
-		if route[i] == slash {
-			token := route[:i]
-			children := nd.getChildren(token)
-			if child, ok := children[token]; ok {
-				if child != nil {
-					return add(child, route[i+1:], item)
-				} else {
-					return ErrInvalidState
-				}
-			} else {
-				child := newNode(nil)
-				children[token] = child
-				return add(child, route[i+1:], item)
-			}
+	if len(wildcard) < 2 {
+		return ErrInvalidState
+	}
+
+	if wildcard[0] == star {
+		if len(wildcard) != len(route) {
+			return ErrInvalidState
+		}
+
+		if nd.catchAll != nil {
+			return ErrDupItem
+		}
+		nd.catchAll = &node{
+			paramName: wildcard[1:],
+			item:      item,
+			priority:  priority,
 		}
+		return nil
+	}
+
+	// :param，捕获到下一个'/'为止。同一个节点下只能有一条param边，
+	// 如果这条边已经存在，新路由的参数名必须和它一致，否则像"/a/:id"和"/a/:name/sub"
+	// 这样的参数名冲突会让旧路由的参数名被悄悄改掉，必须当成非法状态拒绝掉。
+	paramName := wildcard[1:]
+	if nd.param == nil {
+		nd.param = &node{paramName: paramName}
+	} else if nd.param.paramName != paramName {
+		return ErrInvalidState
 	}
+	nd.param.priority += priority
 
-	// 处理URI最后一个的字字符串（以'/'分割）
-	children := nd.getChildren(route)
-	if child, ok := children[route]; ok {
-		if child.item != nil {
+	rest := route[len(wildcard):]
+	if len(rest) == 0 {
+		if nd.param.item != nil {
 			return ErrDupItem
 		}
+		nd.param.item = item
+		return nil
+	}
 
-		child.item = item
-	} else {
-		children[route] = newNode(item)
+	if rest[0] != slash {
+		return ErrInvalidState
 	}
 
-	return nil
+	return nd.param.addRoute(rest, item, priority)
+}
+
+// search 先匹配nd自己这条边的前缀，再把剩余部分交给dispatch继续往下匹配。
+// route是用来做结构匹配的那份（大小写无关模式下已经转成小写），orig是和route
+// 等长、逐字节一一对应的原始大小写版本，用来给:param/*catchall取值。
+func (nd *node) search(route, orig string, result *Result) bool {
+	if len(route) < len(nd.path) || route[:len(nd.path)] != nd.path {
+		return false
+	}
+
+	route = route[len(nd.path):]
+	orig = orig[len(nd.path):]
+	if len(route) == 0 {
+		if nd.item != nil {
+			result.Item = nd.item
+			return true
+		}
+		return false
+	}
+
+	return nd.dispatch(route, orig, result)
+}
+
+// dispatch在nd自己的path已经被消费完之后，依次尝试静态边、:param边、*catchall边
+func (nd *node) dispatch(route, orig string, result *Result) bool {
+	c := route[0]
+	if idx := indexOfSorted(nd.indices, c); idx >= 0 {
+		if nd.statics[idx].search(route, orig, result) {
+			return true
+		}
+	}
+
+	if nd.param != nil {
+		end := strings.IndexByte(route, slash)
+		if end < 0 {
+			end = len(route)
+		}
+		if end > 0 {
+			value := orig[:end]
+			rest := route[end:]
+			restOrig := orig[end:]
+			if len(rest) == 0 {
+				if nd.param.item != nil {
+					addParam(result, nd.param.paramName, value)
+					result.Item = nd.param.item
+					return true
+				}
+			} else if nd.param.search(rest, restOrig, result) {
+				addParam(result, nd.param.paramName, value)
+				return true
+			}
+		}
+	}
+
+	if nd.catchAll != nil && nd.catchAll.item != nil {
+		addParam(result, nd.catchAll.paramName, orig)
+		result.Item = nd.catchAll.item
+		return true
+	}
+
+	return false
 }
 
 func addParam(result *Result, k, v string) {
@@ -188,28 +353,84 @@ func addParam(result *Result, k, v string) {
 	result.Params[k] = v
 }
 
-func match(pat, token string) innerResult {
-	if pat[0] == colon {
-		return innerResult{
-			key:   pat[1:],
-			value: token,
-			named: true,
-			found: true,
+// asciiLower只把ASCII字母折叠成小写，其余字节原样保留，保证返回值和输入
+// 逐字节等长——这是route/orig两份拷贝在search时能按同样的下标对齐切片的前提，
+// 不能像strings.ToLower那样做完整的Unicode大小写折叠（某些字符折叠后UTF-8字节数会变）。
+func asciiLower(s string) string {
+	for i := 0; i < len(s); i++ {
+		if 'A' <= s[i] && s[i] <= 'Z' {
+			b := []byte(s)
+			for ; i < len(b); i++ {
+				if 'A' <= b[i] && b[i] <= 'Z' {
+					b[i] += 'a' - 'A'
+				}
+			}
+			return string(b)
 		}
 	}
 
-	return innerResult{
-		found: pat == token,
+	return s
+}
+
+// longestCommonPrefix返回a、b的最长公共前缀长度
+func longestCommonPrefix(a, b string) int {
+	max := len(a)
+	if len(b) < max {
+		max = len(b)
+	}
+
+	i := 0
+	for i < max && a[i] == b[i] {
+		i++
 	}
+
+	return i
 }
 
-// 创建新的节点, 初始化节点元素和孩子节点
-func newNode(item interface{}) *node {
-	return &node{
-		item: item,
-		children: [2]map[string]*node{
-			make(map[string]*node),
-			make(map[string]*node),
-		},
+// findWildcard在route里找第一个:param或*catchall，返回wildcard本身（不含后续内容）
+// 和它在route里的起始下标；找不到时下标为-1。
+func findWildcard(route string) (string, int) {
+	for start := 0; start < len(route); start++ {
+		c := route[start]
+		if c != colon && c != star {
+			continue
+		}
+
+		for end := start + 1; end < len(route); end++ {
+			if route[end] == slash {
+				return route[start:end], start
+			}
+		}
+
+		return route[start:], start
+	}
+
+	return "", -1
+}
+
+// indexOfSorted在有序的indices里二分查找c，找不到返回-1
+func indexOfSorted(indices string, c byte) int {
+	pos := sort.Search(len(indices), func(i int) bool {
+		return indices[i] >= c
+	})
+	if pos < len(indices) && indices[pos] == c {
+		return pos
 	}
+
+	return -1
+}
+
+// insertSorted把child按c的顺序插入到indices/statics里，保持indices有序以便二分查找
+func insertSorted(indices string, statics []*node, c byte, child *node) (string, []*node) {
+	pos := sort.Search(len(indices), func(i int) bool {
+		return indices[i] >= c
+	})
+
+	newIndices := indices[:pos] + string(c) + indices[pos:]
+	newStatics := make([]*node, 0, len(statics)+1)
+	newStatics = append(newStatics, statics[:pos]...)
+	newStatics = append(newStatics, child)
+	newStatics = append(newStatics, statics[pos:]...)
+
+	return newIndices, newStatics
 }
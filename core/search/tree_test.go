@@ -0,0 +1,144 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreeAddSearch(t *testing.T) {
+	tree := NewTree()
+	assert.Nil(t, tree.Add("/", "root"))
+	assert.Nil(t, tree.Add("/user", "user"))
+	assert.Nil(t, tree.Add("/users", "users"))
+	assert.Nil(t, tree.Add("/users/:id", "user-by-id"))
+	assert.Nil(t, tree.Add("/users/:id/orders", "orders-by-user"))
+	assert.Nil(t, tree.Add("/static/*filepath", "static-file"))
+
+	tests := []struct {
+		route  string
+		item   string
+		params map[string]string
+	}{
+		{"/", "root", nil},
+		{"/user", "user", nil},
+		{"/users", "users", nil},
+		{"/users/123", "user-by-id", map[string]string{"id": "123"}},
+		{"/users/123/orders", "orders-by-user", map[string]string{"id": "123"}},
+		{"/static/a/b/c.js", "static-file", map[string]string{"filepath": "a/b/c.js"}},
+	}
+	for _, test := range tests {
+		result, ok := tree.Search(test.route)
+		assert.True(t, ok, test.route)
+		assert.Equal(t, test.item, result.Item, test.route)
+		if test.params != nil {
+			assert.Equal(t, test.params, result.Params, test.route)
+		}
+	}
+
+	_, ok := tree.Search("/nope")
+	assert.False(t, ok)
+}
+
+func TestTreeErrors(t *testing.T) {
+	tree := NewTree()
+	assert.Equal(t, ErrNotFromRoot, tree.Add("user", "user"))
+	assert.Equal(t, ErrEmptyItem, tree.Add("/user", nil))
+	assert.Equal(t, ErrDupSlash, tree.Add("//user", "user"))
+
+	assert.Nil(t, tree.Add("/user", "user"))
+	assert.Equal(t, ErrDupItem, tree.Add("/user", "user-again"))
+}
+
+func TestTreeConflictingParamName(t *testing.T) {
+	tree := NewTree()
+	assert.Nil(t, tree.Add("/a/:id", "item1"))
+	assert.Equal(t, ErrInvalidState, tree.Add("/a/:name/sub", "item2"))
+
+	result, ok := tree.Search("/a/123")
+	assert.True(t, ok)
+	assert.Equal(t, "item1", result.Item)
+	assert.Equal(t, "123", result.Params["id"])
+}
+
+func TestTreeCaseInsensitive(t *testing.T) {
+	tree := NewTree(CaseInsensitive())
+	assert.Nil(t, tree.Add("/Users/:Id", "user-by-id"))
+
+	result, ok := tree.Search("/users/123")
+	assert.True(t, ok)
+	assert.Equal(t, "user-by-id", result.Item)
+	assert.Equal(t, "123", result.Params["id"])
+}
+
+// 大小写无关只影响静态边的匹配，:param/*catchall捕获到的值必须保留调用方
+// 传入的原始大小写，不能被悄悄转成小写。
+func TestTreeCaseInsensitivePreservesParamCase(t *testing.T) {
+	tree := NewTree(CaseInsensitive())
+	assert.Nil(t, tree.Add("/Users/:Id", "user-by-id"))
+	assert.Nil(t, tree.Add("/Files/*Path", "static-file"))
+
+	result, ok := tree.Search("/users/ABC123")
+	assert.True(t, ok)
+	assert.Equal(t, "ABC123", result.Params["id"])
+
+	result, ok = tree.Search("/FILES/a/B/C.JS")
+	assert.True(t, ok)
+	assert.Equal(t, "a/B/C.JS", result.Params["path"])
+}
+
+func TestTreeAddWithPriorityWalkOrder(t *testing.T) {
+	tree := NewTree()
+	assert.Nil(t, tree.AddWithPriority("/a", "a", 1))
+	assert.Nil(t, tree.AddWithPriority("/b", "b", 5))
+
+	var order []string
+	assert.Nil(t, tree.Walk(func(route string, item interface{}) error {
+		order = append(order, item.(string))
+		return nil
+	}))
+	assert.Equal(t, []string{"b", "a"}, order)
+}
+
+func benchRoutes(n int) (templates, paths []string) {
+	templates = make([]string, 0, n)
+	paths = make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		templates = append(templates, fmt.Sprintf("/service%d/users/:id/orders/%d", i, i))
+		paths = append(paths, fmt.Sprintf("/service%d/users/42/orders/%d", i, i))
+	}
+	return
+}
+
+func BenchmarkTreeSearch(b *testing.B) {
+	templates, paths := benchRoutes(1000)
+	tree := NewTree()
+	for _, route := range templates {
+		if err := tree.Add(route, route); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		path := paths[i%len(paths)]
+		if _, ok := tree.Search(path); !ok {
+			b.Fatal("not found")
+		}
+	}
+}
+
+func BenchmarkTreeAdd(b *testing.B) {
+	templates, _ := benchRoutes(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree := NewTree()
+		for _, route := range templates {
+			if err := tree.Add(route, route); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}